@@ -0,0 +1,74 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice
+
+// EvalOptions configures EvalWithOptions and EvalArgsWithOptions.
+type EvalOptions struct {
+	// MaxDepth caps the nesting depth of "(...)" expressions that
+	// may be evaluated, returning Error("nice: max depth exceeded")
+	// once the budget is exhausted instead of recursing further. 0
+	// means unlimited, the same as Eval/EvalArgs.
+	//
+	// Servers that evaluate untrusted nice-encoded input should set
+	// this (e.g. 10000) to defend against adversarial, deeply nested
+	// input exhausting the goroutine stack.
+	MaxDepth int
+}
+
+// EvalWithOptions is a depth-limited variant of Eval.
+func EvalWithOptions(r Resolver, s []byte, opts EvalOptions) (interface{}, error) {
+	if opts.MaxDepth <= 0 {
+		return Eval(r, s)
+	}
+	return Eval(limitDepth(r, opts.MaxDepth), s)
+}
+
+// EvalArgsWithOptions is a depth-limited variant of EvalArgs.
+func EvalArgsWithOptions(r Resolver, s []byte, opts EvalOptions) ([]interface{}, error) {
+	if opts.MaxDepth <= 0 {
+		return EvalArgs(r, s)
+	}
+	return EvalArgs(limitDepth(r, opts.MaxDepth), s)
+}
+
+// limitDepth wraps r so that every name resolution consumes one
+// unit of the remaining budget. The Handler it returns ignores
+// whatever resolver Eval would normally hand it and substitutes one
+// with the budget already decremented, so that any further
+// recursion the handler triggers -- via EvalArgs, Eval or
+// Resolver.Recurse -- is counted against the same budget.
+//
+// A name that is itself a "(...)" expression (the case
+// Resolver.Recurse exists for) is handled here rather than being
+// delegated to r, so that a bare "(((...)))" input with no pipes at
+// all -- which never reaches a handler's args, only ever recurses
+// through the name position -- is governed by the budget too.
+func limitDepth(r Resolver, remaining int) Resolver {
+	return func(name []byte) Handler {
+		if remaining <= 0 {
+			return ErrorHandler(Error("nice: max depth exceeded"))
+		}
+		next := limitDepth(r, remaining-1)
+
+		if len(name) > 0 && name[0] == '(' {
+			v, err := Eval(next, name)
+			if err != nil {
+				return ErrorHandler(err)
+			}
+			h, ok := v.(Handler)
+			if !ok {
+				return ErrorHandler(Error("nice: not a function"))
+			}
+			return func(_ Resolver, args []byte) (interface{}, error) {
+				return h(next, args)
+			}
+		}
+
+		handler := r(name)
+		return func(_ Resolver, args []byte) (interface{}, error) {
+			return handler(next, args)
+		}
+	}
+}
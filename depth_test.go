@@ -0,0 +1,112 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice_test
+
+import (
+	"bytes"
+	"github.com/tvastar/nice"
+	"testing"
+)
+
+// nestResolver treats "nest" as a function that just evaluates and
+// returns its single argument, letting a test build an
+// arbitrarily deep call chain without needing the json package.
+func nestResolver(name []byte) nice.Handler {
+	if string(name) != "nest" {
+		return nice.ErrorHandler(nice.Error("depth_test: unknown " + string(name)))
+	}
+	return func(r nice.Resolver, args []byte) (interface{}, error) {
+		values, err := nice.EvalArgs(r, args)
+		if err != nil {
+			return nil, err
+		}
+		return values[0], nil
+	}
+}
+
+func nestedInput(depth int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < depth; i++ {
+		buf.WriteString("(nest|")
+	}
+	buf.WriteString("done")
+	for i := 0; i < depth; i++ {
+		buf.WriteByte(')')
+	}
+	return buf.Bytes()
+}
+
+func TestEvalWithOptionsMaxDepth(t *testing.T) {
+	input := nestedInput(100000)
+
+	_, err := nice.EvalWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{MaxDepth: 1000})
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Fatal("Expected max depth error", err)
+	}
+}
+
+func TestEvalWithOptionsWithinBudget(t *testing.T) {
+	input := nestedInput(10)
+
+	v, err := nice.EvalWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{MaxDepth: 1000})
+	if err != nil || !bytes.Equal([]byte(v.(nice.Raw)), []byte("done")) {
+		t.Error("Unexpected result", v, err)
+	}
+}
+
+func TestEvalArgsWithOptionsMaxDepth(t *testing.T) {
+	input := nestedInput(100000)
+
+	_, err := nice.EvalArgsWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{MaxDepth: 1000})
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Fatal("Expected max depth error", err)
+	}
+}
+
+func TestEvalArgsWithOptionsWithinBudget(t *testing.T) {
+	input := []byte("a|" + string(nestedInput(10)))
+
+	values, err := nice.EvalArgsWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{MaxDepth: 1000})
+	if err != nil || len(values) != 2 {
+		t.Fatal("Unexpected result", values, err)
+	}
+	if !bytes.Equal([]byte(values[1].(nice.Raw)), []byte("done")) {
+		t.Error("Unexpected result", values)
+	}
+}
+
+// bareNestedInput builds a pure "(((...)))" expression with no
+// pipes at all, so that every layer is recursed into via the
+// function-name position (Resolver.Recurse) rather than via a
+// handler's args.
+func bareNestedInput(depth int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < depth; i++ {
+		buf.WriteByte('(')
+	}
+	buf.WriteString("done")
+	for i := 0; i < depth; i++ {
+		buf.WriteByte(')')
+	}
+	return buf.Bytes()
+}
+
+func TestEvalWithOptionsMaxDepthBareParens(t *testing.T) {
+	input := bareNestedInput(100000)
+
+	_, err := nice.EvalWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{MaxDepth: 1000})
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Fatal("Expected max depth error", err)
+	}
+}
+
+func TestEvalWithOptionsUnlimited(t *testing.T) {
+	input := nestedInput(5000)
+
+	v, err := nice.EvalWithOptions(nice.Resolver(nestResolver).Recurse, input, nice.EvalOptions{})
+	if err != nil || !bytes.Equal([]byte(v.(nice.Raw)), []byte("done")) {
+		t.Error("Unexpected result", v, err)
+	}
+}
@@ -6,15 +6,29 @@
 //
 // The Encode function encodes generic JSON types into the nice
 // format. This includes map[string]interface{}, []interface{},
-// string, int, float and nil.  It errors out for all other types.
+// string, int, float, bool, []byte and nil.  It errors out for all
+// other types.
+//
+// []byte is encoded as a base64-encoded json:bytes value rather
+// than as a json:string, preserving arbitrary binary data without
+// the escaping overhead a string encoding of the same bytes would
+// require.
 //
 // The Resolver resolves all the types produced by the Encode function
 // (though it only decodes all numbers to float64). The Decode
 // function provides a simple wrapper using the provided Resolver.
+//
+// NewDecoder provides a streaming variant of Decode built on top of
+// nice.Decoder: it reads one top-level value at a time from an
+// io.Reader rather than requiring the whole input up front. It is
+// meant for JSON-lines-style feeds of many values rather than for
+// reducing the memory used to decode a single very large value,
+// which it still buffers in full before evaluating.
 package json
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"github.com/tvastar/nice"
 	"io"
@@ -32,26 +46,106 @@ func Encode(v interface{}) ([]byte, error) {
 
 // Decode converts the byte sequence into a  JSON-like value
 func Decode(b []byte) (interface{}, error) {
-	return nice.Eval(nice.Resolver(Resolve).Recurse, b)
+	return DecodeWithOptions(b, DecoderOptions{})
+}
+
+// DecoderOptions controls the behaviour of DecodeWithOptions.
+type DecoderOptions struct {
+	// UseNumber causes json:number, json:int and json:float values
+	// to decode to a Number (preserving the original textual
+	// representation) instead of being parsed into a float64. This
+	// avoids the precision loss a large int64 would otherwise
+	// suffer by round-tripping through float64.
+	UseNumber bool
+
+	// MaxDepth caps the nesting depth of the value being decoded, 0
+	// meaning unlimited. Set this (e.g. 10000) when decoding
+	// untrusted input to defend against adversarial, deeply nested
+	// documents exhausting the goroutine stack.
+	MaxDepth int
 }
 
-// Resolve resolves all the type names implemented by this package.
+// DecodeWithOptions is a variant of Decode that accepts
+// DecoderOptions.
+func DecodeWithOptions(b []byte, opts DecoderOptions) (interface{}, error) {
+	resolver := nice.Resolver(resolverFor(opts)).Recurse
+	if opts.MaxDepth > 0 {
+		return nice.EvalWithOptions(resolver, b, nice.EvalOptions{MaxDepth: opts.MaxDepth})
+	}
+	return nice.Eval(resolver, b)
+}
+
+// Decoder reads a stream of JSON-like values from an io.Reader,
+// decoding one top-level value at a time instead of requiring the
+// whole input as a single byte slice. This is the variant to reach
+// for with JSON-lines-style feeds of many values.
+type Decoder struct {
+	d    *nice.Decoder
+	opts DecoderOptions
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderWithOptions(r, DecoderOptions{})
+}
+
+// NewDecoderWithOptions returns a Decoder that reads from r,
+// honouring opts (UseNumber and MaxDepth) for every value it
+// decodes. Servers reading untrusted, JSON-lines-style input should
+// set MaxDepth to defend against adversarial, deeply nested values.
+func NewDecoderWithOptions(r io.Reader, opts DecoderOptions) *Decoder {
+	return &Decoder{d: nice.NewDecoder(r), opts: opts}
+}
+
+// More reports whether there is another value to read.
+func (d *Decoder) More() bool {
+	return d.d.More()
+}
+
+// Decode reads and returns the next JSON-like value.
+func (d *Decoder) Decode() (interface{}, error) {
+	resolver := nice.Resolver(resolverFor(d.opts)).Recurse
+	if d.opts.MaxDepth > 0 {
+		return d.d.DecodeWithOptions(resolver, nice.EvalOptions{MaxDepth: d.opts.MaxDepth})
+	}
+	return d.d.Decode(resolver)
+}
+
+// Resolve resolves all the type names implemented by this package,
+// decoding json:number, json:int and json:float to float64. Use
+// DecodeWithOptions with UseNumber set to preserve the original
+// numeric text (and so int64 precision) instead.
 func Resolve(name []byte) nice.Handler {
-	switch string(name) {
-	case "json:null":
-		return evalNull
-	case "json:string":
-		return evalString
-	case "json:number":
-		return evalNumber
-	case "json:array":
-		return evalArray
-	case "json:map":
-		return evalMap
-	}
-	return nice.Handler(func(_ nice.Resolver, _ []byte) (interface{}, error) {
-		return nil, errors.New("json: unknown type: " + string(name))
-	})
+	return resolverFor(DecoderOptions{})(name)
+}
+
+// resolverFor returns a Resolver for all the type names implemented
+// by this package, honouring opts.UseNumber for numeric types.
+func resolverFor(opts DecoderOptions) nice.Resolver {
+	return func(name []byte) nice.Handler {
+		switch string(name) {
+		case "json:null":
+			return evalNull
+		case "json:string":
+			return evalString
+		case "json:number", "json:int", "json:float":
+			if opts.UseNumber {
+				return evalNumberAsNumber
+			}
+			return evalNumber
+		case "json:bool":
+			return evalBool
+		case "json:bytes":
+			return evalBytes
+		case "json:array":
+			return evalArray
+		case "json:map":
+			return evalMap
+		}
+		return nice.Handler(func(_ nice.Resolver, _ []byte) (interface{}, error) {
+			return nil, errors.New("json: unknown type: " + string(name))
+		})
+	}
 }
 
 func evalNull(r nice.Resolver, args []byte) (interface{}, error) {
@@ -66,6 +160,30 @@ func evalNumber(r nice.Resolver, args []byte) (interface{}, error) {
 	return nil, err
 }
 
+func evalNumberAsNumber(r nice.Resolver, args []byte) (interface{}, error) {
+	v, err := evalString(r, args)
+	if err != nil {
+		return nil, err
+	}
+	return Number(v.(string)), nil
+}
+
+func evalBool(r nice.Resolver, args []byte) (interface{}, error) {
+	v, err := evalString(r, args)
+	if err != nil {
+		return nil, err
+	}
+	return strconv.ParseBool(v.(string))
+}
+
+func evalBytes(r nice.Resolver, args []byte) (interface{}, error) {
+	v, err := evalString(r, args)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(v.(string))
+}
+
 func evalString(r nice.Resolver, args []byte) (interface{}, error) {
 	values, err := nice.EvalArgs(r, args)
 	if err != nil {
@@ -126,22 +244,28 @@ func EncodeTo(w io.Writer, v interface{}) error {
 		call(w, "json:null")
 	case int:
 		arg := strconv.FormatInt(int64(v), 10)
-		call(w, "json:number", []byte(arg))
+		call(w, "json:int", []byte(arg))
 	case int32:
 		arg := strconv.FormatInt(int64(v), 10)
-		call(w, "json:number", []byte(arg))
+		call(w, "json:int", []byte(arg))
 	case int64:
 		arg := strconv.FormatInt(v, 10)
-		call(w, "json:number", []byte(arg))
+		call(w, "json:int", []byte(arg))
 	case float32:
 		arg := strconv.FormatFloat(float64(v), 'E', -1, 64)
-		call(w, "json:number", []byte(arg))
+		call(w, "json:float", []byte(arg))
 	case float64:
 		arg := strconv.FormatFloat(v, 'E', -1, 64)
-		call(w, "json:number", []byte(arg))
+		call(w, "json:float", []byte(arg))
 	case string:
 		arg := nice.Escape([]byte(v))
 		call(w, "json:string", arg)
+	case bool:
+		arg := strconv.FormatBool(v)
+		call(w, "json:bool", []byte(arg))
+	case []byte:
+		arg := base64.StdEncoding.EncodeToString(v)
+		call(w, "json:bytes", []byte(arg))
 	case []interface{}:
 		must(w.Write([]byte("(json:array")))
 		for _, elt := range v {
@@ -5,8 +5,12 @@
 package json_test
 
 import (
+	"bytes"
+	"github.com/tvastar/nice"
 	"github.com/tvastar/nice/json"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -20,6 +24,8 @@ func TestJSON(t *testing.T) {
 		int64(3),
 		float32(1.5),
 		float64(1.5),
+		true,
+		false,
 		nil,
 	})
 	if err != nil {
@@ -38,6 +44,8 @@ func TestJSON(t *testing.T) {
 		float64(3),
 		float64(1.5),
 		float64(1.5),
+		true,
+		false,
 		nil,
 	}
 	if !reflect.DeepEqual(decoded, expected) {
@@ -45,6 +53,114 @@ func TestJSON(t *testing.T) {
 	}
 }
 
+func TestUseNumber(t *testing.T) {
+	big := int64(1)<<53 + 1 // not exactly representable as a float64
+	encoded, err := json.Encode(big)
+	if err != nil {
+		t.Fatal("Unexpected encode error", err)
+	}
+
+	decoded, err := json.DecodeWithOptions(encoded, json.DecoderOptions{UseNumber: true})
+	if err != nil {
+		t.Fatal("Decode failed", err)
+	}
+	n, ok := decoded.(json.Number)
+	if !ok {
+		t.Fatal("Expected a Number", decoded)
+	}
+	got, err := n.Int64()
+	if err != nil || got != big {
+		t.Error("Unexpected round-trip", got, err)
+	}
+
+	// without UseNumber, the existing float64 behavior is preserved.
+	decoded, err = json.Decode(encoded)
+	if err != nil || decoded != float64(big) {
+		t.Error("Unexpected default decode", decoded, err)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	raw := make([]byte, 256)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+
+	encoded, err := json.Encode(raw)
+	if err != nil {
+		t.Fatal("Unexpected encode error", err)
+	}
+	decoded, err := json.Decode(encoded)
+	if err != nil {
+		t.Fatal("Decode failed", err, string(encoded))
+	}
+	if !reflect.DeepEqual(decoded, raw) {
+		t.Error("Unexpected round-trip", decoded)
+	}
+}
+
+func TestDecodeWithOptionsMaxDepth(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 100000; i++ {
+		buf.WriteString("(json:array|")
+	}
+	buf.WriteString("(json:null)")
+	for i := 0; i < 100000; i++ {
+		buf.WriteByte(')')
+	}
+
+	_, err := json.DecodeWithOptions(buf.Bytes(), json.DecoderOptions{MaxDepth: 1000})
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Error("Expected max depth error", err)
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	encoded, err := json.Encode(map[string]interface{}{"hello": "world"})
+	if err != nil {
+		t.Fatal("Unexpected encode error", err)
+	}
+
+	d := json.NewDecoder(strings.NewReader(string(encoded) + string(encoded)))
+	for kk := 0; kk < 2; kk++ {
+		if !d.More() {
+			t.Fatal("Expected more values")
+		}
+		decoded, err := d.Decode()
+		if err != nil {
+			t.Fatal("Decode failed", err)
+		}
+		expected := map[string]interface{}{"hello": "world"}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Error("Unexpected decoded value", decoded)
+		}
+	}
+
+	if d.More() {
+		t.Error("Expected no more values")
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Error("Expected EOF", err)
+	}
+}
+
+func TestNewDecoderWithOptionsMaxDepth(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 100000; i++ {
+		buf.WriteString("(json:array|")
+	}
+	buf.WriteString("(json:null)")
+	for i := 0; i < 100000; i++ {
+		buf.WriteByte(')')
+	}
+
+	d := json.NewDecoderWithOptions(&buf, json.DecoderOptions{MaxDepth: 1000})
+	_, err := d.Decode()
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Error("Expected max depth error", err)
+	}
+}
+
 func TestErrors(t *testing.T) {
 	if _, err := json.Encode([]int{2}); err.Error() != "json: unknown type" {
 		t.Error("Unexpected", err)
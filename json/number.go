@@ -0,0 +1,30 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package json
+
+import "strconv"
+
+// Number represents a JSON number literal as its original textual
+// representation, the same way encoding/json's Number does. A
+// Number is only returned when DecoderOptions.UseNumber is set (via
+// DecodeWithOptions or NewDecoderWithOptions); this avoids the
+// precision loss that parsing into a float64 would otherwise cause
+// for large int64 values.
+type Number string
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// String returns the number's original textual representation.
+func (n Number) String() string {
+	return string(n)
+}
@@ -0,0 +1,254 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nicer
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+
+	"github.com/tvastar/nice"
+	"github.com/tvastar/nice/json"
+)
+
+// structValue is the intermediate representation of a decoded
+// "struct:Name" expression, produced by Resolve before assign
+// converts it into the caller's concrete Go value.
+type structValue struct {
+	name   string
+	fields map[string]interface{}
+}
+
+// Resolve resolves "struct:*" type names registered by this package
+// and delegates everything else (the primitive, array and map type
+// names) to github.com/tvastar/nice/json.Resolve.
+func Resolve(name []byte) nice.Handler {
+	if bytes.HasPrefix(name, []byte("struct:")) {
+		return evalStruct(string(name[len("struct:"):]))
+	}
+	return json.Resolve(name)
+}
+
+func evalStruct(name string) nice.Handler {
+	return func(r nice.Resolver, args []byte) (interface{}, error) {
+		values, err := nice.EvalArgs(r, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(values)%2 != 0 {
+			return nil, errors.New("nicer: struct expects an even number of args")
+		}
+
+		fields := make(map[string]interface{}, len(values)/2)
+		for kk := 0; kk < len(values); kk += 2 {
+			key, ok := values[kk].(nice.Raw)
+			if !ok {
+				return nil, errors.New("nicer: struct field names must be strings")
+			}
+			fields[string(nice.Unescape([]byte(key)))] = values[kk+1]
+		}
+		return structValue{name: name, fields: fields}, nil
+	}
+}
+
+// Unmarshal decodes data into v, which must be a non-nil pointer.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("nicer: Unmarshal requires a non-nil pointer")
+	}
+
+	decoded, err := nice.Eval(nice.Resolver(Resolve).Recurse, data)
+	if err != nil {
+		return err
+	}
+	return assign(rv.Elem(), decoded)
+}
+
+func assign(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	for dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+
+	if dst.Kind() == reflect.Interface {
+		return assignInterface(dst, src)
+	}
+
+	switch sv := src.(type) {
+	case structValue:
+		if dst.Kind() != reflect.Struct {
+			return typeError(dst, "struct:"+sv.name)
+		}
+		return assignStructFields(dst, sv.fields)
+	case map[string]interface{}:
+		return assignMap(dst, sv)
+	case []interface{}:
+		return assignSlice(dst, sv)
+	case []byte:
+		if dst.Kind() != reflect.Slice || dst.Type().Elem().Kind() != reflect.Uint8 {
+			return typeError(dst, "json:bytes")
+		}
+		dst.SetBytes(sv)
+		return nil
+	case string:
+		if dst.Kind() != reflect.String {
+			return typeError(dst, "json:string")
+		}
+		dst.SetString(sv)
+		return nil
+	case bool:
+		if dst.Kind() != reflect.Bool {
+			return typeError(dst, "json:bool")
+		}
+		dst.SetBool(sv)
+		return nil
+	case float64:
+		return assignNumber(dst, sv)
+	}
+	return errors.New("nicer: cannot decode a " + reflect.TypeOf(src).String())
+}
+
+func assignNumber(dst reflect.Value, n float64) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(n)
+	default:
+		return typeError(dst, "json:number")
+	}
+	return nil
+}
+
+func assignStructFields(dst reflect.Value, fields map[string]interface{}) error {
+	for _, f := range fieldsFor(dst.Type()) {
+		val, ok := fields[f.name]
+		if !ok {
+			continue
+		}
+		if err := assign(fieldByIndexAlloc(dst, f.index), val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assignMap(dst reflect.Value, src map[string]interface{}) error {
+	if dst.Kind() != reflect.Map {
+		return typeError(dst, "json:map")
+	}
+	if dst.Type().Key().Kind() != reflect.String {
+		return errors.New("nicer: map keys must be strings")
+	}
+
+	m := reflect.MakeMapWithSize(dst.Type(), len(src))
+	elemType := dst.Type().Elem()
+	for k, v := range src {
+		ev := reflect.New(elemType).Elem()
+		if err := assign(ev, v); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), ev)
+	}
+	dst.Set(m)
+	return nil
+}
+
+func assignSlice(dst reflect.Value, src []interface{}) error {
+	if dst.Kind() != reflect.Slice && dst.Kind() != reflect.Array {
+		return typeError(dst, "json:array")
+	}
+
+	var s reflect.Value
+	if dst.Kind() == reflect.Slice {
+		s = reflect.MakeSlice(dst.Type(), len(src), len(src))
+	} else {
+		s = dst
+	}
+	for i, v := range src {
+		if i >= s.Len() {
+			break
+		}
+		if err := assign(s.Index(i), v); err != nil {
+			return err
+		}
+	}
+	if dst.Kind() == reflect.Slice {
+		dst.Set(s)
+	}
+	return nil
+}
+
+// assignInterface decodes src into dst, a field of interface type.
+// A struct:Name expression is only reconstructed as its concrete Go
+// type if that type was registered with Register; otherwise (and
+// for every other type) it falls back to the same generic values
+// encoding/json would produce for an interface{} target.
+func assignInterface(dst reflect.Value, src interface{}) error {
+	sv, ok := src.(structValue)
+	if !ok {
+		dst.Set(reflect.ValueOf(toGeneric(src)))
+		return nil
+	}
+
+	t, ok := lookupType(sv.name)
+	if !ok {
+		dst.Set(reflect.ValueOf(toGeneric(src)))
+		return nil
+	}
+
+	nv := reflect.New(t)
+	if err := assignStructFields(nv.Elem(), sv.fields); err != nil {
+		return err
+	}
+	if !nv.Elem().Type().AssignableTo(dst.Type()) {
+		return errors.New("nicer: struct:" + sv.name + " does not implement " + dst.Type().String())
+	}
+	dst.Set(nv.Elem())
+	return nil
+}
+
+// toGeneric recursively converts a decoded value into the same
+// plain map[string]interface{} / []interface{} / primitive shape
+// that github.com/tvastar/nice/json.Decode would produce, turning
+// any nested structValue into a map[string]interface{}.
+func toGeneric(src interface{}) interface{} {
+	switch v := src.(type) {
+	case structValue:
+		m := make(map[string]interface{}, len(v.fields))
+		for k, fv := range v.fields {
+			m[k] = toGeneric(fv)
+		}
+		return m
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = toGeneric(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = toGeneric(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func typeError(dst reflect.Value, got string) error {
+	return errors.New("nicer: cannot decode " + got + " into " + dst.Type().String())
+}
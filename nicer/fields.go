@@ -0,0 +1,156 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nicer
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldInfo describes one field of a struct as seen by Marshal and
+// Unmarshal: its nice name and index path (anonymous struct fields
+// are flattened, so index may have more than one element) and
+// whether it should be skipped when empty.
+type fieldInfo struct {
+	name      string
+	omitempty bool
+	index     []int
+}
+
+var (
+	fieldsMu    sync.RWMutex
+	fieldsCache = map[reflect.Type][]fieldInfo{}
+)
+
+// fieldsFor returns the fields of struct type t, caching the result
+// so that the struct tag is only parsed once per type.
+func fieldsFor(t reflect.Type) []fieldInfo {
+	fieldsMu.RLock()
+	fi, ok := fieldsCache[t]
+	fieldsMu.RUnlock()
+	if ok {
+		return fi
+	}
+
+	fi = collectFields(t, nil)
+
+	fieldsMu.Lock()
+	fieldsCache[t] = fi
+	fieldsMu.Unlock()
+	return fi
+}
+
+func collectFields(t reflect.Type, index []int) []fieldInfo {
+	var result []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+
+		if f.Anonymous {
+			elem := f.Type
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct {
+				result = append(result, collectFields(elem, idx)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, omitempty, skip := parseTag(f)
+		if skip {
+			continue
+		}
+		result = append(result, fieldInfo{name: name, omitempty: omitempty, index: idx})
+	}
+	return result
+}
+
+// fieldByIndex walks the same index path reflect.Value.FieldByIndex
+// does, except that a nil pointer embedded partway through the path
+// (a pointer-embedded anonymous field with no value set) is reported
+// via ok=false instead of panicking, so that such a field is simply
+// omitted from encoding.
+func fieldByIndex(v reflect.Value, index []int) (result reflect.Value, ok bool) {
+	for i, idx := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc is fieldByIndex's counterpart for Unmarshal: a
+// nil pointer embedded partway through the path is allocated rather
+// than treated as absent, since the caller is about to assign into
+// it.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(idx)
+	}
+	return v
+}
+
+// parseTag reads the "nice" struct tag, returning the field's
+// encoded name, whether it is marked omitempty, and whether it
+// should be skipped entirely (tag is "-").
+func parseTag(f reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := f.Tag.Get("nice")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name = f.Name
+	if tag == "" {
+		return name, false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Package nicer implements a reflection-based codec that marshals
+// and unmarshals arbitrary Go values -- structs, slices, maps,
+// pointers and primitives -- to and from the nice format, the same
+// way encoding/json marshals Go values without forcing callers
+// through map[string]interface{}.
+//
+// Structs are encoded as "(struct:Name|field1|val1|field2|val2|...)"
+// using a "nice" struct tag (`nice:"fieldname,omitempty"`) to rename
+// or skip fields, the same way the "json" tag works for
+// encoding/json. Primitives, slices and maps reuse the type tags
+// from github.com/tvastar/nice/json.
+//
+// A struct's concrete type can only be recovered from a
+// "struct:Name" expression when decoding into an interface{} field
+// if it has been registered with Register; otherwise it decodes as
+// a map[string]interface{}, mirroring how encoding/json decodes an
+// object into an interface{}.
+package nicer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/tvastar/nice"
+	"github.com/tvastar/nice/json"
+)
+
+// Marshal encodes v into its nice representation.
+func Marshal(v interface{}) ([]byte, error) {
+	w := &bytes.Buffer{}
+	if err := MarshalTo(w, v); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// MarshalTo is Marshal writing its output to w.
+func MarshalTo(w io.Writer, v interface{}) error {
+	return encodeValue(w, reflect.ValueOf(v))
+}
+
+func encodeValue(w io.Writer, v reflect.Value) error {
+	if !v.IsValid() {
+		return json.EncodeTo(w, nil)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return json.EncodeTo(w, nil)
+		}
+		return encodeValue(w, v.Elem())
+	case reflect.Struct:
+		return encodeStruct(w, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return json.EncodeTo(w, v.Bytes())
+		}
+		return encodeSlice(w, v)
+	case reflect.Array:
+		return encodeSlice(w, v)
+	case reflect.Map:
+		return encodeMap(w, v)
+	case reflect.String:
+		return json.EncodeTo(w, v.String())
+	case reflect.Bool:
+		return json.EncodeTo(w, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.EncodeTo(w, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.EncodeTo(w, int64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return json.EncodeTo(w, v.Float())
+	}
+	return errors.New("nicer: unsupported type: " + v.Type().String())
+}
+
+func encodeStruct(w io.Writer, v reflect.Value) error {
+	if _, err := w.Write([]byte("(struct:")); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(v.Type().Name())); err != nil {
+		return err
+	}
+	for _, f := range fieldsFor(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitempty && isEmptyValue(fv)) {
+			continue
+		}
+		if _, err := w.Write([]byte{'|'}); err != nil {
+			return err
+		}
+		if _, err := w.Write(nice.Escape([]byte(f.name))); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'|'}); err != nil {
+			return err
+		}
+		if err := encodeValue(w, fv); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{')'})
+	return err
+}
+
+func encodeSlice(w io.Writer, v reflect.Value) error {
+	if _, err := w.Write([]byte("(json:array")); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if _, err := w.Write([]byte{'|'}); err != nil {
+			return err
+		}
+		if err := encodeValue(w, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{')'})
+	return err
+}
+
+func encodeMap(w io.Writer, v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return errors.New("nicer: map keys must be strings")
+	}
+	if _, err := w.Write([]byte("(json:map")); err != nil {
+		return err
+	}
+	iter := v.MapRange()
+	for iter.Next() {
+		if _, err := w.Write([]byte{'|'}); err != nil {
+			return err
+		}
+		if _, err := w.Write(nice.Escape([]byte(iter.Key().String()))); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'|'}); err != nil {
+			return err
+		}
+		if err := encodeValue(w, iter.Value()); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{')'})
+	return err
+}
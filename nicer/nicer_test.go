@@ -0,0 +1,193 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nicer_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/tvastar/nice/nicer"
+)
+
+type Address struct {
+	City string `nice:"city"`
+	Zip  string `nice:"zip,omitempty"`
+}
+
+type Person struct {
+	Address
+	Name    string   `nice:"name"`
+	Age     int      `nice:"age"`
+	Tags    []string `nice:"tags,omitempty"`
+	private string
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	p := Person{
+		Address: Address{City: "Springfield"},
+		Name:    "Homer",
+		Age:     39,
+		Tags:    []string{"doh", "d'oh"},
+	}
+
+	encoded, err := nicer.Marshal(p)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+
+	var got Person
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+
+	if !reflect.DeepEqual(got, p) {
+		t.Error("Unexpected round-trip", got, p, string(encoded))
+	}
+}
+
+func TestOmitempty(t *testing.T) {
+	p := Person{Name: "Bart", Age: 10}
+	encoded, err := nicer.Marshal(p)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+
+	// zip and tags are omitempty and zero-valued, so they should not
+	// appear in the encoded form at all.
+	if bytes.Contains(encoded, []byte("zip")) || bytes.Contains(encoded, []byte("tags")) {
+		t.Error("Expected omitempty fields to be omitted", string(encoded))
+	}
+}
+
+type Registered struct {
+	Value int `nice:"value"`
+}
+
+func init() {
+	nicer.Register("Registered", Registered{})
+}
+
+func TestInterfaceRoundTrip(t *testing.T) {
+	type Wrapper struct {
+		V interface{} `nice:"v"`
+	}
+
+	w := Wrapper{V: Registered{Value: 42}}
+	encoded, err := nicer.Marshal(w)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+
+	var got Wrapper
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+
+	if !reflect.DeepEqual(got.V, Registered{Value: 42}) {
+		t.Error("Unexpected round-trip", got.V)
+	}
+}
+
+func TestUnregisteredInterfaceFallsBackToMap(t *testing.T) {
+	type Unregistered struct {
+		Value int `nice:"value"`
+	}
+	type Wrapper struct {
+		V interface{} `nice:"v"`
+	}
+
+	w := Wrapper{V: Unregistered{Value: 7}}
+	encoded, err := nicer.Marshal(w)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+
+	var got Wrapper
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+
+	m, ok := got.V.(map[string]interface{})
+	if !ok || m["value"] != float64(7) {
+		t.Error("Unexpected fallback value", got.V)
+	}
+}
+
+type Employee struct {
+	*Address
+	Name string `nice:"name"`
+}
+
+func TestMarshalUnmarshalPointerEmbedding(t *testing.T) {
+	e := Employee{Address: &Address{City: "Shelbyville"}, Name: "Moe"}
+
+	encoded, err := nicer.Marshal(e)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+	if bytes.Contains(encoded, []byte("Address")) {
+		t.Error("Expected Address fields to be flattened, not nested", string(encoded))
+	}
+
+	var got Employee
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Error("Unexpected round-trip", got, e, string(encoded))
+	}
+}
+
+func TestMarshalUnmarshalNilPointerEmbedding(t *testing.T) {
+	e := Employee{Name: "Moe"}
+
+	encoded, err := nicer.Marshal(e)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+
+	var got Employee
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Error("Unexpected round-trip", got, e, string(encoded))
+	}
+}
+
+func TestMarshalUnmarshalBytes(t *testing.T) {
+	type Blob struct {
+		Data []byte `nice:"data"`
+	}
+
+	want := Blob{Data: []byte{0, 1, 2, 255}}
+	encoded, err := nicer.Marshal(want)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+	if !bytes.Contains(encoded, []byte("json:bytes")) {
+		t.Error("Expected json:bytes encoding", string(encoded))
+	}
+
+	var got Blob
+	if err := nicer.Unmarshal(encoded, &got); err != nil {
+		t.Fatal("Unexpected unmarshal error", err, string(encoded))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("Unexpected round-trip", got, want)
+	}
+}
+
+func TestMarshalPrimitives(t *testing.T) {
+	encoded, err := nicer.Marshal(42)
+	if err != nil {
+		t.Fatal("Unexpected marshal error", err)
+	}
+	var got int
+	if err := nicer.Unmarshal(encoded, &got); err != nil || got != 42 {
+		t.Error("Unexpected round-trip", got, err)
+	}
+}
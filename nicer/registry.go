@@ -0,0 +1,43 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nicer
+
+import (
+	"reflect"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	nameToType = map[string]reflect.Type{}
+)
+
+// Register associates name with the concrete type of v, so that a
+// "struct:name" expression decoded into an interface{} field can be
+// reconstructed as that concrete type. v's value is only used to
+// obtain its type; it is never retained.
+//
+// Marshal writes a struct's unqualified Go type name
+// (reflect.Type.Name()) as its struct:name, so a type is normally
+// registered under that name. Types are never registered
+// automatically: a struct:name with no registered type decodes into
+// an interface{} field as a map[string]interface{}.
+func Register(name string, v interface{}) {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	registryMu.Lock()
+	nameToType[name] = t
+	registryMu.Unlock()
+}
+
+func lookupType(name string) (reflect.Type, bool) {
+	registryMu.RLock()
+	t, ok := nameToType[name]
+	registryMu.RUnlock()
+	return t, ok
+}
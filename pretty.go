@@ -0,0 +1,258 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+)
+
+// prettyNode is the parse tree used by Indent, Compact and
+// Canonicalize. Unlike Eval, building it never consults a Resolver,
+// so it works even when some of the type names it contains aren't
+// known to any Handler.
+//
+// Its parser is deliberately more lenient than Eval: it skips spaces,
+// tabs, carriage returns and newlines around "(", "|" and ")", and
+// trims leading and trailing white space (as bytes.TrimSpace defines
+// it, Unicode white space included) from raw (atomic) values, since
+// none of that whitespace is otherwise distinguishable
+// from the structural whitespace Indent inserts. That means a value
+// whose leading or trailing whitespace is actually significant won't
+// round-trip through Indent followed by Compact -- encode such
+// values (e.g. with json:bytes) rather than relying on it.
+type prettyNode struct {
+	raw  []byte
+	list bool
+	name *prettyNode
+	args []*prettyNode
+}
+
+func parsePretty(s []byte) (*prettyNode, error) {
+	n, rest, err := parsePrettyNode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return nil, Error("nice: unexpected trailing data")
+	}
+	return n, nil
+}
+
+func parsePrettyNode(s []byte) (*prettyNode, []byte, error) {
+	s = bytes.TrimLeft(s, " \t\r\n")
+	if len(s) == 0 || s[0] != '(' {
+		return parsePrettyRaw(s)
+	}
+
+	n := &prettyNode{list: true}
+	name, rest, err := parsePrettyNode(s[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	n.name = name
+
+	for {
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+		if len(rest) == 0 {
+			return nil, nil, Error("nice: missing )")
+		}
+		switch rest[0] {
+		case ')':
+			return n, rest[1:], nil
+		case '|':
+			arg, next, err := parsePrettyNode(rest[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			n.args = append(n.args, arg)
+			rest = next
+		default:
+			return nil, nil, Error("nice: mismatched )")
+		}
+	}
+}
+
+// parsePrettyRaw scans a raw (atomic) value off the front of s,
+// stopping -- without consuming -- at the first unescaped "|" or ")"
+// that isn't balanced by a nested "(", mirroring the nesting count
+// used by EvalArgs. Running off the end of s (an unbracketed
+// top-level expression) is treated the same way Eval treats it: the
+// whole remainder is the value.
+func parsePrettyRaw(s []byte) (*prettyNode, []byte, error) {
+	nesting := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			nesting++
+		case ')':
+			if nesting == 0 {
+				return &prettyNode{raw: bytes.TrimSpace(s[:i])}, s[i:], nil
+			}
+			nesting--
+		case '|':
+			if nesting == 0 {
+				return &prettyNode{raw: bytes.TrimSpace(s[:i])}, s[i:], nil
+			}
+		}
+	}
+	return &prettyNode{raw: bytes.TrimSpace(s)}, nil, nil
+}
+
+// Indent parses src (which must be a valid nice expression) and
+// writes an indented form of it to dst: each pipe-separated
+// argument of a list is written on its own line, prefixed by prefix
+// and one copy of indent per nesting level, mirroring
+// encoding/json's Indent. As with encoding/json, prefix and indent
+// should be made up of whitespace (space and tab); Compact and
+// Canonicalize rely on that to tell the whitespace Indent inserted
+// apart from the expression's actual content.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	n, err := parsePretty(src)
+	if err != nil {
+		return err
+	}
+	writeIndented(dst, n, prefix, indent, 0)
+	return nil
+}
+
+func writeIndented(dst *bytes.Buffer, n *prettyNode, prefix, indent string, depth int) {
+	if !n.list {
+		dst.Write(n.raw)
+		return
+	}
+
+	dst.WriteByte('(')
+	writeIndented(dst, n.name, prefix, indent, depth)
+	for _, arg := range n.args {
+		dst.WriteByte('\n')
+		dst.WriteString(prefix)
+		for i := 0; i < depth+1; i++ {
+			dst.WriteString(indent)
+		}
+		dst.WriteByte('|')
+		writeIndented(dst, arg, prefix, indent, depth+1)
+	}
+	dst.WriteByte(')')
+}
+
+// Compact parses src and writes its compact (no inserted
+// whitespace) form to dst, mirroring encoding/json's Compact. It is
+// the inverse of Indent.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	n, err := parsePretty(src)
+	if err != nil {
+		return err
+	}
+	writeCompact(dst, n)
+	return nil
+}
+
+func writeCompact(dst *bytes.Buffer, n *prettyNode) {
+	if !n.list {
+		dst.Write(n.raw)
+		return
+	}
+
+	dst.WriteByte('(')
+	writeCompact(dst, n.name)
+	for _, arg := range n.args {
+		dst.WriteByte('|')
+		writeCompact(dst, arg)
+	}
+	dst.WriteByte(')')
+}
+
+// Canonicalize returns a canonical encoding of src: the keys of
+// every json:map are sorted lexicographically and every
+// json:number, json:int and json:float value is reformatted to a
+// single consistent representation. Two expressions encoding the
+// same logical value -- but produced independently, e.g. from a Go
+// map with unspecified iteration order -- canonicalize to the same
+// byte sequence, making the result suitable for hashing or
+// content-addressed storage.
+func Canonicalize(src []byte) ([]byte, error) {
+	n, err := parsePretty(src)
+	if err != nil {
+		return nil, err
+	}
+	c, err := canonicalize(n)
+	if err != nil {
+		return nil, err
+	}
+	dst := &bytes.Buffer{}
+	writeCompact(dst, c)
+	return dst.Bytes(), nil
+}
+
+func canonicalize(n *prettyNode) (*prettyNode, error) {
+	if !n.list {
+		return n, nil
+	}
+
+	name, err := canonicalize(n.name)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]*prettyNode, len(n.args))
+	for i, arg := range n.args {
+		if args[i], err = canonicalize(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	switch string(name.raw) {
+	case "json:map":
+		if err := sortMapArgs(args); err != nil {
+			return nil, err
+		}
+	case "json:int":
+		normalizeArg(args, func(s string) (string, bool) {
+			v, err := strconv.ParseInt(s, 10, 64)
+			return strconv.FormatInt(v, 10), err == nil
+		})
+	case "json:number", "json:float":
+		normalizeArg(args, func(s string) (string, bool) {
+			v, err := strconv.ParseFloat(s, 64)
+			return strconv.FormatFloat(v, 'E', -1, 64), err == nil
+		})
+	}
+
+	return &prettyNode{list: true, name: name, args: args}, nil
+}
+
+func sortMapArgs(args []*prettyNode) error {
+	if len(args)%2 != 0 {
+		return Error("nice: json:map expects an even number of args")
+	}
+
+	type pair struct{ key, value *prettyNode }
+	pairs := make([]pair, len(args)/2)
+	for i := range pairs {
+		if args[2*i].list {
+			return Error("nice: json:map allows string keys only")
+		}
+		pairs[i] = pair{args[2*i], args[2*i+1]}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(Unescape(pairs[i].key.raw), Unescape(pairs[j].key.raw)) < 0
+	})
+	for i, p := range pairs {
+		args[2*i], args[2*i+1] = p.key, p.value
+	}
+	return nil
+}
+
+func normalizeArg(args []*prettyNode, format func(string) (string, bool)) {
+	if len(args) != 1 || args[0].list {
+		return
+	}
+	if v, ok := format(string(args[0].raw)); ok {
+		args[0] = &prettyNode{raw: []byte(v)}
+	}
+}
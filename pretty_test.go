@@ -0,0 +1,105 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice_test
+
+import (
+	"bytes"
+	"github.com/tvastar/nice"
+	"testing"
+)
+
+func TestIndent(t *testing.T) {
+	src := "(json:map|hello|(json:array|(json:number|1)|(json:number|2)))"
+	var buf bytes.Buffer
+	if err := nice.Indent(&buf, []byte(src), "", "  "); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expected := "(json:map\n" +
+		"  |hello\n" +
+		"  |(json:array\n" +
+		"    |(json:number\n" +
+		"      |1)\n" +
+		"    |(json:number\n" +
+		"      |2)))"
+	if buf.String() != expected {
+		t.Errorf("Unexpected indent: got %q want %q", buf.String(), expected)
+	}
+}
+
+func TestIndentCompactRoundTrip(t *testing.T) {
+	src := "(json:map|hello|(json:array|(json:number|1)|(json:number|2)))"
+
+	var indented bytes.Buffer
+	if err := nice.Indent(&indented, []byte(src), "  ", "  "); err != nil {
+		t.Fatal("Unexpected indent error", err)
+	}
+
+	var compacted bytes.Buffer
+	if err := nice.Compact(&compacted, indented.Bytes()); err != nil {
+		t.Fatal("Unexpected compact error", err)
+	}
+
+	if compacted.String() != src {
+		t.Errorf("Unexpected round-trip: got %q want %q", compacted.String(), src)
+	}
+}
+
+func TestCompactAtomic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := nice.Compact(&buf, []byte("hello")); err != nil || buf.String() != "hello" {
+		t.Error("Unexpected", buf.String(), err)
+	}
+}
+
+func TestCanonicalizeSortsMapKeys(t *testing.T) {
+	src := "(json:map|zip|1|air|2)"
+	got, err := nice.Canonicalize([]byte(src))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expected := "(json:map|air|2|zip|1)"
+	if string(got) != expected {
+		t.Errorf("Unexpected canonical form: got %q want %q", got, expected)
+	}
+}
+
+func TestCanonicalizeNormalizesNumbers(t *testing.T) {
+	src := "(json:array|(json:int|007)|(json:float|1.50))"
+	got, err := nice.Canonicalize([]byte(src))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expected := "(json:array|(json:int|7)|(json:float|1.5E+00))"
+	if string(got) != expected {
+		t.Errorf("Unexpected canonical form: got %q want %q", got, expected)
+	}
+}
+
+func TestCanonicalizeDeterministic(t *testing.T) {
+	a := "(json:map|zip|(json:int|1)|air|(json:int|02))"
+	b := "(json:map|air|(json:int|2)|zip|(json:int|01))"
+
+	ca, err := nice.Canonicalize([]byte(a))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	cb, err := nice.Canonicalize([]byte(b))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	if string(ca) != string(cb) {
+		t.Errorf("Expected matching canonical forms: %q vs %q", ca, cb)
+	}
+}
+
+func TestCanonicalizeRejectsUnbalancedMap(t *testing.T) {
+	if _, err := nice.Canonicalize([]byte("(json:map|zip)")); err != nice.Error("nice: json:map expects an even number of args") {
+		t.Error("Unexpected error", err)
+	}
+}
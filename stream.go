@@ -0,0 +1,257 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice
+
+import (
+	"bufio"
+	"io"
+)
+
+// Delim represents one of the three structural bytes of the nice
+// syntax -- OpenList ("("), Pipe ("|") or CloseList (")") -- as
+// returned by Tokenizer.Token.
+type Delim byte
+
+// String returns the single-character representation of the delimiter.
+func (d Delim) String() string {
+	return string(rune(d))
+}
+
+// The structural tokens produced by a Tokenizer. The fourth token,
+// an atomic byte run, is represented by Raw rather than a Delim
+// since it carries a value.
+const (
+	OpenList  = Delim('(')
+	Pipe      = Delim('|')
+	CloseList = Delim(')')
+)
+
+type tokenizerState int
+
+const (
+	stateExprStart tokenizerState = iota
+	stateAfterExpr
+)
+
+// Tokenizer reads a single nice-encoded expression from an
+// io.Reader and splits it into a stream of tokens: OpenList, Pipe,
+// CloseList (all Delim) and atomic byte runs (Raw). It is the
+// streaming analogue of Eval/EvalArgs, which require the whole
+// expression as a byte slice up front -- Tokenizer instead tracks
+// bracket nesting and "\" escapes itself, buffering only the bytes
+// of the atomic token currently being read.
+//
+// Token returns io.EOF once the expression has been fully
+// consumed: for an unbracketed (atomic) expression that means the
+// underlying reader is exhausted, for a bracketed one it means the
+// matching CloseList has been read. A Decoder drives a fresh
+// Tokenizer per expression so that several expressions can be read
+// back to back from the same io.Reader.
+type Tokenizer struct {
+	r     *bufio.Reader
+	depth int
+	state tokenizerState
+	done  bool
+}
+
+// NewTokenizer returns a Tokenizer that reads from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{r: bufio.NewReader(r)}
+}
+
+// Token returns the next token: a Delim (OpenList, Pipe or
+// CloseList) or a Raw atomic byte run. It returns io.EOF when the
+// current expression has been fully read.
+func (t *Tokenizer) Token() (interface{}, error) {
+	if t.done {
+		return nil, io.EOF
+	}
+
+	if t.state == stateAfterExpr {
+		c, err := t.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, Error("nice: missing )")
+			}
+			return nil, err
+		}
+		switch c {
+		case '|':
+			t.state = stateExprStart
+			return Pipe, nil
+		case ')':
+			t.depth--
+			if t.depth == 0 {
+				t.done = true
+			}
+			return CloseList, nil
+		default:
+			return nil, Error("nice: mismatched )")
+		}
+	}
+
+	c, err := t.r.ReadByte()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err == nil {
+		if c == '(' {
+			t.depth++
+			return OpenList, nil
+		}
+		if err := t.r.UnreadByte(); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, rerr := t.readRaw()
+	if t.depth == 0 {
+		t.done = true
+		return Raw(raw), nil
+	}
+	if rerr != nil {
+		return nil, Error("nice: missing )")
+	}
+	t.state = stateAfterExpr
+	return Raw(raw), nil
+}
+
+// readRaw consumes the bytes of a single atomic token. At depth 0
+// (an unbracketed top-level expression) it reads through to EOF,
+// exactly as Eval treats such input as a single Raw value. Inside a
+// list it stops -- without consuming -- at the first unescaped "|"
+// or ")" that isn't balanced by a nested "(", mirroring the nesting
+// count used by EvalArgs.
+func (t *Tokenizer) readRaw() ([]byte, error) {
+	var buf []byte
+	nesting := 0
+	for {
+		c, err := t.r.ReadByte()
+		if err == io.EOF {
+			if t.depth == 0 {
+				return buf, nil
+			}
+			return buf, io.EOF
+		}
+		if err != nil {
+			return buf, err
+		}
+		switch {
+		case c == '\\':
+			buf = append(buf, c)
+			if c2, err := t.r.ReadByte(); err == nil {
+				buf = append(buf, c2)
+			}
+		case c == '(':
+			nesting++
+			buf = append(buf, c)
+		case c == ')':
+			if t.depth > 0 && nesting == 0 {
+				_ = t.r.UnreadByte()
+				return buf, nil
+			}
+			nesting--
+			buf = append(buf, c)
+		case c == '|':
+			if t.depth > 0 && nesting == 0 {
+				_ = t.r.UnreadByte()
+				return buf, nil
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+}
+
+// Decoder reads successive nice-encoded expressions from an
+// io.Reader, evaluating one top-level expression at a time. This
+// mirrors encoding/json's Decoder and is meant for JSON-lines-style
+// feeds of many expressions, where holding the whole stream in
+// memory as a single byte slice (as Eval requires) isn't practical.
+// Decode itself still buffers the current expression in full before
+// evaluating it, so it does not reduce peak memory for a single very
+// large expression -- only Token, which streams token by token,
+// does that.
+type Decoder struct {
+	r   *bufio.Reader
+	tok *Tokenizer
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// More reports whether there is another expression to read,
+// skipping any whitespace separating expressions.
+func (d *Decoder) More() bool {
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			d.r.ReadByte()
+		default:
+			return true
+		}
+	}
+}
+
+// Token returns the next token (a Delim or a Raw) from the current
+// expression. It returns io.EOF once at the end of each expression;
+// the call after that starts on the next expression, or returns
+// io.EOF again if there are no more expressions.
+func (d *Decoder) Token() (interface{}, error) {
+	if d.tok == nil {
+		if !d.More() {
+			return nil, io.EOF
+		}
+		d.tok = NewTokenizer(d.r)
+	}
+	tok, err := d.tok.Token()
+	if err == io.EOF {
+		d.tok = nil
+	}
+	return tok, err
+}
+
+// Decode reads one top-level expression -- buffering only that
+// expression, not the rest of the stream -- and evaluates it with
+// r. It returns io.EOF once there are no more expressions to read.
+func (d *Decoder) Decode(r Resolver) (interface{}, error) {
+	return d.DecodeWithOptions(r, EvalOptions{})
+}
+
+// DecodeWithOptions is a depth-limited variant of Decode, for
+// callers reading untrusted input off the wire where a single
+// expression could otherwise recurse arbitrarily deep.
+func (d *Decoder) DecodeWithOptions(r Resolver, opts EvalOptions) (interface{}, error) {
+	if !d.More() {
+		return nil, io.EOF
+	}
+	tok := NewTokenizer(d.r)
+
+	var buf []byte
+	for {
+		v, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case Delim:
+			buf = append(buf, byte(v))
+		case Raw:
+			buf = append(buf, []byte(v)...)
+		}
+	}
+
+	return EvalWithOptions(r, buf, opts)
+}
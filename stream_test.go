@@ -0,0 +1,140 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package nice_test
+
+import (
+	"bytes"
+	"github.com/tvastar/nice"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	tok := nice.NewTokenizer(strings.NewReader("(add|x|(y|z))"))
+	expected := []interface{}{
+		nice.OpenList,
+		nice.Raw("add"),
+		nice.Pipe,
+		nice.Raw("x"),
+		nice.Pipe,
+		nice.OpenList,
+		nice.Raw("y"),
+		nice.Pipe,
+		nice.Raw("z"),
+		nice.CloseList,
+		nice.CloseList,
+	}
+
+	got := []interface{}(nil)
+	for {
+		v, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		got = append(got, v)
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Error("Unexpected tokens", got, expected)
+	}
+}
+
+func TestTokenizerAtomic(t *testing.T) {
+	tok := nice.NewTokenizer(strings.NewReader("hello"))
+	v, err := tok.Token()
+	if err != nil || !reflect.DeepEqual(v, nice.Raw("hello")) {
+		t.Error("Unexpected", v, err)
+	}
+	if _, err := tok.Token(); err != io.EOF {
+		t.Error("Expected EOF", err)
+	}
+}
+
+func TestTokenizerEscape(t *testing.T) {
+	tok := nice.NewTokenizer(strings.NewReader(`(x|a\|b)`))
+	expected := []interface{}{
+		nice.OpenList,
+		nice.Raw("x"),
+		nice.Pipe,
+		nice.Raw(`a\|b`),
+		nice.CloseList,
+	}
+
+	got := []interface{}(nil)
+	for {
+		v, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Error("Unexpected tokens", got, expected)
+	}
+}
+
+func TestTokenizerMissingClose(t *testing.T) {
+	tok := nice.NewTokenizer(strings.NewReader("(x"))
+	for {
+		_, err := tok.Token()
+		if err != nil {
+			if err != nice.Error("nice: missing )") {
+				t.Error("Unexpected error", err)
+			}
+			return
+		}
+	}
+}
+
+func TestDecoderMultiple(t *testing.T) {
+	r := func(name []byte) nice.Handler {
+		return func(_ nice.Resolver, args []byte) (interface{}, error) {
+			return string(name), nil
+		}
+	}
+
+	d := nice.NewDecoder(strings.NewReader("(add)\n(sub)\nraw"))
+	got := []interface{}(nil)
+	for d.More() {
+		v, err := d.Decode(nice.Resolver(r).Recurse)
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		got = append(got, v)
+	}
+
+	expected := []interface{}{"add", "sub", nice.Raw("raw")}
+	if !reflect.DeepEqual(got, expected) {
+		t.Error("Unexpected decoded values", got, expected)
+	}
+}
+
+func TestDecoderDecodeWithOptionsMaxDepth(t *testing.T) {
+	input := nestedInput(100000)
+
+	d := nice.NewDecoder(bytes.NewReader(input))
+	_, err := d.DecodeWithOptions(nice.Resolver(nestResolver).Recurse, nice.EvalOptions{MaxDepth: 1000})
+	if err != nice.Error("nice: max depth exceeded") {
+		t.Fatal("Expected max depth error", err)
+	}
+}
+
+func TestDecoderEOF(t *testing.T) {
+	d := nice.NewDecoder(strings.NewReader(""))
+	if d.More() {
+		t.Error("Expected no more values")
+	}
+	if _, err := d.Decode(nice.Resolver(nil).Recurse); err != io.EOF {
+		t.Error("Expected EOF", err)
+	}
+}